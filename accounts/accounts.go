@@ -0,0 +1,63 @@
+// Package accounts loads the -accounts manifest used for cross-account
+// scanning and assumes the configured IAM role in each target account.
+package accounts
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Account identifies a single AWS account to scan and the role to assume
+// into it.
+type Account struct {
+	ID         string `json:"id" yaml:"id"`
+	RoleName   string `json:"roleName,omitempty" yaml:"roleName,omitempty"`
+	ExternalID string `json:"externalId,omitempty" yaml:"externalId,omitempty"`
+}
+
+// Manifest is the -accounts file format: a default role name/external ID
+// applied to every account that doesn't override them, plus the account
+// list itself (ignored when -organization auto-discovery is used).
+type Manifest struct {
+	RoleName   string    `json:"roleName" yaml:"roleName"`
+	ExternalID string    `json:"externalId,omitempty" yaml:"externalId,omitempty"`
+	Accounts   []Account `json:"accounts" yaml:"accounts"`
+}
+
+// LoadManifest reads a Manifest from a JSON or YAML file, selected by its
+// extension (.json, or .yml/.yaml), and fills every account missing a
+// RoleName/ExternalID from the manifest-level defaults.
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading accounts manifest %s: %w", path, err)
+	}
+
+	var m Manifest
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		err = json.Unmarshal(data, &m)
+	case ".yml", ".yaml":
+		err = yaml.Unmarshal(data, &m)
+	default:
+		return nil, fmt.Errorf("unsupported accounts manifest extension %q (expected .json, .yml or .yaml)", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing accounts manifest %s: %w", path, err)
+	}
+
+	for i := range m.Accounts {
+		if m.Accounts[i].RoleName == "" {
+			m.Accounts[i].RoleName = m.RoleName
+		}
+		if m.Accounts[i].ExternalID == "" {
+			m.Accounts[i].ExternalID = m.ExternalID
+		}
+	}
+	return &m, nil
+}
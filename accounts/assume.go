@@ -0,0 +1,38 @@
+package accounts
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// AssumeRoleConfig returns a copy of baseCfg whose credentials are
+// assumed into account via sts:AssumeRole, using account.RoleName and
+// (if set) account.ExternalID. The returned config's credentials are
+// retrieved eagerly so AssumeRole failures surface here rather than on
+// the first API call made with it.
+func AssumeRoleConfig(ctx context.Context, baseCfg aws.Config, account Account) (aws.Config, error) {
+	if account.RoleName == "" {
+		return aws.Config{}, fmt.Errorf("account %s: no role name configured", account.ID)
+	}
+
+	roleARN := fmt.Sprintf("arn:aws:iam::%s:role/%s", account.ID, account.RoleName)
+	stsClient := sts.NewFromConfig(baseCfg)
+	provider := stscreds.NewAssumeRoleProvider(stsClient, roleARN, func(o *stscreds.AssumeRoleOptions) {
+		o.RoleSessionName = "whoami-scanner"
+		if account.ExternalID != "" {
+			o.ExternalID = aws.String(account.ExternalID)
+		}
+	})
+
+	assumedCfg := baseCfg.Copy()
+	assumedCfg.Credentials = aws.NewCredentialsCache(provider)
+
+	if _, err := assumedCfg.Credentials.Retrieve(ctx); err != nil {
+		return aws.Config{}, fmt.Errorf("assuming role %s: %w", roleARN, err)
+	}
+	return assumedCfg, nil
+}
@@ -0,0 +1,39 @@
+package accounts
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/organizations"
+	orgtypes "github.com/aws/aws-sdk-go-v2/service/organizations/types"
+	"github.com/aws/smithy-go/ptr"
+)
+
+// Discover lists every ACTIVE account in the AWS Organization reachable
+// from baseCfg's credentials (typically the management account or a
+// delegated administrator), assigning roleName/externalID to each so the
+// result can be passed straight to AssumeRoleConfig.
+func Discover(ctx context.Context, baseCfg aws.Config, roleName, externalID string) ([]Account, error) {
+	client := organizations.NewFromConfig(baseCfg)
+
+	var found []Account
+	paginator := organizations.NewListAccountsPaginator(client, &organizations.ListAccountsInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("listing organization accounts: %w", err)
+		}
+		for _, acct := range page.Accounts {
+			if acct.Status != orgtypes.AccountStatusActive {
+				continue
+			}
+			found = append(found, Account{
+				ID:         ptr.ToString(acct.Id),
+				RoleName:   roleName,
+				ExternalID: externalID,
+			})
+		}
+	}
+	return found, nil
+}
@@ -0,0 +1,123 @@
+// Package graph exports whoAMI scan results to a Neo4j database so they
+// can be explored with Cypher (e.g. "find all instances running AMIs
+// from untrusted owners" across accounts and regions).
+package graph
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+
+	"github.com/DataDog/whoAMI-scanner/policy"
+	"github.com/DataDog/whoAMI-scanner/scanner"
+)
+
+// Config holds the connection details for the target Neo4j database.
+type Config struct {
+	URI      string
+	Username string
+	Password string
+}
+
+// Ingestor upserts Accounts, Regions, Instances, AMIs and AmiOwners as
+// nodes, connected by MOUNTS, OWNED_BY and TRUSTS relationships.
+type Ingestor struct {
+	driver neo4j.DriverWithContext
+}
+
+// NewIngestor connects to the Neo4j database described by cfg and
+// verifies connectivity before returning.
+func NewIngestor(ctx context.Context, cfg Config) (*Ingestor, error) {
+	driver, err := neo4j.NewDriverWithContext(cfg.URI, neo4j.BasicAuth(cfg.Username, cfg.Password, ""))
+	if err != nil {
+		return nil, fmt.Errorf("creating neo4j driver: %w", err)
+	}
+	if err := driver.VerifyConnectivity(ctx); err != nil {
+		return nil, fmt.Errorf("connecting to neo4j at %s: %w", cfg.URI, err)
+	}
+	return &Ingestor{driver: driver}, nil
+}
+
+// Close releases the underlying Neo4j driver.
+func (i *Ingestor) Close(ctx context.Context) error {
+	return i.driver.Close(ctx)
+}
+
+// IngestResults upserts every AMI in results (and the instances running
+// it) under its own AMI.AccountID -- one or many accounts, depending on
+// whether results came from a single scan or scanner.MergeResults --
+// along with the relationships connecting them.
+func (i *Ingestor) IngestResults(ctx context.Context, results *scanner.Results) error {
+	session := i.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		for status, amis := range map[policy.Classification]map[string]scanner.AMI{
+			policy.Verified:   results.VerifiedAMIs,
+			policy.Unverified: results.UnverifiedAMIs,
+			policy.Untrusted:  results.UntrustedAMIs,
+			policy.Suspicious: results.SuspiciousAMIs,
+			policy.Unknown:    results.UnknownAMIs,
+			policy.Private:    results.PrivateAMIs,
+		} {
+			for _, ami := range amis {
+				if err := i.ingestAMI(ctx, tx, status, ami); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return fmt.Errorf("ingesting results: %w", err)
+	}
+	return nil
+}
+
+// ingestAMI upserts a single AMI node, its owner node, the account and
+// region it was found in, and every instance referencing it.
+func (i *Ingestor) ingestAMI(ctx context.Context, tx neo4j.ManagedTransaction, st policy.Classification, ami scanner.AMI) error {
+	_, err := tx.Run(ctx, `
+		MERGE (account:Account {id: $accountID})
+		MERGE (region:Region {name: $region})
+		MERGE (ami:AMI {id: $amiID})
+		SET ami.Name = $name,
+		    ami.Description = $description,
+		    ami.Public = $public,
+		    ami.Region = $region,
+		    ami.status = $status
+		MERGE (ami)-[:FOUND_IN]->(region)
+		MERGE (owner:AmiOwner {id: $ownerID})
+		SET owner.alias = $ownerAlias
+		MERGE (ami)-[:OWNED_BY]->(owner)
+		MERGE (account)-[:TRUSTS {status: $status}]->(owner)
+	`, map[string]any{
+		"accountID":   ami.AccountID,
+		"region":      ami.Region,
+		"amiID":       ami.ID,
+		"name":        ami.Name,
+		"description": ami.Description,
+		"public":      ami.Public,
+		"status":      string(st),
+		"ownerID":     ami.OwnerID,
+		"ownerAlias":  ami.OwnerAlias,
+	})
+	if err != nil {
+		return fmt.Errorf("upserting AMI %s: %w", ami.ID, err)
+	}
+
+	for _, instanceID := range ami.Instances {
+		if _, err := tx.Run(ctx, `
+			MATCH (ami:AMI {id: $amiID})
+			MERGE (instance:Instance {id: $instanceID})
+			MERGE (instance)-[:MOUNTS]->(ami)
+		`, map[string]any{
+			"amiID":      ami.ID,
+			"instanceID": instanceID,
+		}); err != nil {
+			return fmt.Errorf("upserting instance %s: %w", instanceID, err)
+		}
+	}
+	return nil
+}
@@ -4,39 +4,66 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/service/ec2"
-	"github.com/aws/aws-sdk-go-v2/service/sts"
-	"github.com/aws/smithy-go/ptr"
-	"github.com/fatih/color"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/whoAMI-scanner/accounts"
+	"github.com/DataDog/whoAMI-scanner/graph"
+	"github.com/DataDog/whoAMI-scanner/policy"
+	"github.com/DataDog/whoAMI-scanner/reporters"
+	"github.com/DataDog/whoAMI-scanner/scanner"
+	"github.com/fatih/color"
 )
 
-type AMI struct {
-	ID          string
-	Region      string
-	OwnerAlias  string
-	OwnerID     string
-	Name        string
-	Description string
-	Public      string
-}
+const toolVersion = "dev"
 
 var verbose bool
 
+// formatsFlag collects repeated -format flags into an ordered list.
+type formatsFlag []string
+
+func (f *formatsFlag) String() string { return strings.Join(*f, ",") }
+func (f *formatsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
 func main() {
 	// Parse command-line arguments
 	var profile string
 	var region string
 	var output string
+	var concurrency int
+	var maxRetries int
+	var graphURI string
+	var graphUser string
+	var graphPassword string
+	var trustPolicyPath string
+	var accountsPath string
+	var organization bool
+	var formats formatsFlag
 	flag.StringVar(&profile, "profile", "", "AWS profile name [Default: Default profile, IMDS, or environment variables]")
 	flag.StringVar(&region, "region", "", "AWS region [Default: All regions]")
 	flag.BoolVar(&verbose, "verbose", false, "Enable verbose output for detailed status updates")
-	flag.StringVar(&output, "output", "", "Specify file path/name for csv report)")
+	flag.StringVar(&output, "output", "", "Specify file path/name for the report(s) [Default: no file written]")
+	flag.Var(&formats, "format", "Report format to write: csv, json, or sarif (repeatable) [Default: csv]")
+	flag.IntVar(&concurrency, "concurrency", 10, "Ceiling on concurrent AWS API calls in flight at once, shared across accounts, regions and AMI batches")
+	flag.IntVar(&maxRetries, "max-retries", 5, "Maximum AWS SDK attempts per API call, counting the first attempt")
+	flag.StringVar(&graphURI, "graph-uri", "", "Neo4j bolt URI to export findings to (e.g. bolt://localhost:7687) [Default: graph export disabled]")
+	flag.StringVar(&graphUser, "graph-user", "", "Neo4j username")
+	flag.StringVar(&graphPassword, "graph-password", "", "Neo4j password")
+	flag.StringVar(&trustPolicyPath, "trust-policy", "", "Path to a YAML/JSON trust policy file [Default: built-in policy.Default()]")
+	flag.StringVar(&accountsPath, "accounts", "", "Path to a YAML/JSON accounts manifest for cross-account scanning via AssumeRole [Default: scan only the caller's account]")
+	flag.BoolVar(&organization, "organization", false, "Auto-discover member accounts via organizations:ListAccounts instead of reading them from the accounts manifest (requires -accounts for the shared role name)")
 	flag.Parse()
 
+	if len(formats) == 0 {
+		formats = formatsFlag{"csv"}
+	}
+
 	if output != "" {
 		PreparePath(output)
 	}
@@ -45,171 +72,61 @@ func main() {
 		fmt.Println("[*] Verbose mode enabled.")
 	}
 
-	cfg, err := config.LoadDefaultConfig(context.TODO(), config.WithSharedConfigProfile(profile), config.WithRegion("us-east-1"))
-	if err != nil {
-		color.Red("Error loading AWS config: %v", err)
-		os.Exit(1)
+	trustPolicy := policy.Default()
+	if trustPolicyPath != "" {
+		loaded, err := policy.Load(trustPolicyPath)
+		if err != nil {
+			color.Red("Error loading trust policy: %v", err)
+			os.Exit(1)
+		}
+		trustPolicy = loaded
 	}
 
-	if region != "" {
-		cfg.Region = region
+	ctx := context.TODO()
+	scanConfig := scanner.Config{
+		Profile:     profile,
+		Region:      region,
+		Concurrency: concurrency,
+		MaxRetries:  maxRetries,
+		Verbose:     verbose,
+		TrustPolicy: trustPolicy,
 	}
 
-	ec2Client := ec2.NewFromConfig(cfg)
-	stsClient := sts.NewFromConfig(cfg)
+	var results *scanner.Results
+	var accountErrs []accountScanError
 
-	// Get account ID
-	callerIdentity, err := stsClient.GetCallerIdentity(context.TODO(), &sts.GetCallerIdentityInput{})
-	if err != nil {
-		color.Red("Error fetching account ID: %v", err)
-		os.Exit(1)
-	}
-	_ = *callerIdentity.Account
-
-	// Fetch regions
-	var regions []string
-	if region == "" {
-		describeRegionsOutput, err := ec2Client.DescribeRegions(context.TODO(), &ec2.DescribeRegionsInput{})
+	scanTime := time.Now()
+	fmt.Println("\nStarting AMI analysis...")
+	if accountsPath != "" {
+		var err error
+		results, accountErrs, err = scanAccounts(ctx, accountsPath, organization, scanConfig)
 		if err != nil {
-			color.Red("Error fetching regions: %v", err)
+			color.Red("Error scanning accounts: %v", err)
 			os.Exit(1)
 		}
-		for _, r := range describeRegionsOutput.Regions {
-			regions = append(regions, *r.RegionName)
-		}
 	} else {
-		regions = []string{region}
-	}
-
-	processedAMIs := make(map[string]bool)
-	verifiedAMIs := make(map[string]AMI)
-	unverifiedAMIs := make(map[string]AMI)
-	unknownAMIs := make(map[string]AMI)
-	privateAMIs := make(map[string]AMI)
-	totalInstances := 0
-
-	fmt.Println("\nStarting AMI analysis...")
-	// Loop through regions
-	for _, region := range regions {
-		if verbose {
-			fmt.Printf("[*] Checking region %s\n", region)
-		}
-		cfg.Region = region
-		ec2Client := ec2.NewFromConfig(cfg)
-
-		// Fetch instances
-		instancesOutput, err := ec2Client.DescribeInstances(context.TODO(), &ec2.DescribeInstancesInput{})
+		s, err := scanner.New(ctx, scanConfig)
 		if err != nil {
-			color.Red("Error fetching instances for region %s: %v", region, err)
-			continue
+			color.Red("Error initializing scanner: %v", err)
+			os.Exit(1)
 		}
-
-		instanceIDs := []string{}
-		for _, reservation := range instancesOutput.Reservations {
-			for _, instance := range reservation.Instances {
-				instanceIDs = append(instanceIDs, *instance.InstanceId)
-			}
+		results, err = s.Run(ctx)
+		if err != nil {
+			color.Red("Error running scan: %v", err)
+			os.Exit(1)
 		}
+	}
 
-		totalInstances += len(instanceIDs)
-		if len(instanceIDs) == 0 {
-			continue
+	if graphURI != "" {
+		if err := exportToGraph(ctx, graphURI, graphUser, graphPassword, results); err != nil {
+			color.Red("Error exporting to graph database: %v", err)
 		}
+	}
 
-		for i, instanceID := range instanceIDs {
-			// Fetch instance details
-			instanceDetail, err := ec2Client.DescribeInstances(context.TODO(), &ec2.DescribeInstancesInput{
-				InstanceIds: []string{instanceID},
-			})
-			if err != nil {
-				color.Red("Error fetching details for instance %s: %v", instanceID, err)
-				continue
-			}
-
-			for _, reservation := range instanceDetail.Reservations {
-				for _, instance := range reservation.Instances {
-					amiID := *instance.ImageId
-
-					if processedAMIs[amiID] {
-						if verbose {
-							color.Cyan("[%d/%d][%s] %s already processed. Skipping.", i+1, len(instanceIDs), region, amiID)
-						}
-						continue
-					}
-					processedAMIs[amiID] = true
-
-					if verbose {
-						fmt.Printf("[%d/%d][%s] %s being analyzed (Instance: %s)\n", i+1, len(instanceIDs), region, amiID, instanceID)
-					}
-
-					// Fetch AMI details
-					imageOutput, err := ec2Client.DescribeImages(context.TODO(), &ec2.DescribeImagesInput{
-						ImageIds: []string{amiID},
-					})
-					if err != nil {
-						if verbose {
-							color.Red("Error fetching AMI details for %s: %v", amiID, err)
-						}
-						continue
-					}
-					if len(imageOutput.Images) == 0 {
-						color.Yellow("[%d/%d][%s] %s has been deleted or made private.", i+1, len(instanceIDs), region, amiID)
-						unknownAMIs[amiID] = AMI{
-							ID:          amiID,
-							Region:      region,
-							OwnerAlias:  "Unknown",
-							Public:      "Unknown",
-							OwnerID:     "Unknown",
-							Name:        "Unknown",
-							Description: "Unknown",
-						}
-						continue
-					}
-					var publicString string
-					for _, image := range imageOutput.Images {
-
-						if *image.Public {
-							publicString = "Public"
-						} else {
-							publicString = "Private"
-						}
-						ami := AMI{
-							ID:          amiID,
-							Region:      region,
-							OwnerAlias:  ptr.ToString(image.ImageOwnerAlias),
-							OwnerID:     ptr.ToString(image.OwnerId),
-							Name:        ptr.ToString(image.Name),
-							Description: ptr.ToString(image.Description),
-							Public:      publicString,
-						}
-
-						if *image.Public {
-							if ami.OwnerAlias != "" {
-								if ami.OwnerAlias == "amazon" {
-									if verbose {
-										color.Green("[%d/%d][%s] %s is a community AMI from a verified account.", i+1, len(instanceIDs), region, amiID)
-									}
-									verifiedAMIs[amiID] = ami
-								} else if ami.OwnerAlias == "self" {
-									if verbose {
-										color.Green("[%d/%d][%s] %s is private.", i+1, len(instanceIDs), region, amiID)
-									}
-									ami.OwnerAlias = "self"
-									privateAMIs[amiID] = ami
-								}
-							} else {
-								color.Red("[%d/%d][%s] %s is a community AMI from an unverified account.", i+1, len(instanceIDs), region, amiID)
-								unverifiedAMIs[amiID] = ami
-							}
-						} else {
-							if verbose {
-								color.Green("[%d/%d][%s] %s is private.", i+1, len(instanceIDs), region, amiID)
-							}
-							privateAMIs[amiID] = ami
-						}
-					}
-				}
-			}
+	if len(accountErrs) > 0 {
+		fmt.Println("\nAccount Errors:")
+		for _, ae := range accountErrs {
+			color.Red("  %s: %v", ae.AccountID, ae.Err)
 		}
 	}
 
@@ -227,48 +144,187 @@ func main() {
 	color.Red("|                    | unless they are from accounts you control. If not from    |")
 	color.Red("|                    | your accounts, look to replace these with AMIs from       |")
 	color.Red("|                    | verified accounts                                         |")
+	color.Red("| Untrusted          | AMIs from owners explicitly denied by the trust policy     |")
+	color.Red("| Suspicious         | Unverified AMIs whose Name impersonates a known vendor     |")
+	color.Red("|                    | image; a strong signal of the whoAMI attack pattern        |")
 	fmt.Println("+------------------+-------------------------------------------------------------+")
 
 	// Output results
 	fmt.Println("\nSummary:")
-	color.Cyan("          Total Instances: %d", totalInstances)
-	color.Cyan("               Total AMIs: %d", len(processedAMIs))
-	color.Green("            Private AMIs: %d", len(privateAMIs))
-	color.Green("  Public & Verified AMIs: %d", len(verifiedAMIs))
-	color.Yellow("  AMIs w/ Unknown status: %d", len(unknownAMIs))
-	color.Red("Public & Unverified AMIs: %d", len(unverifiedAMIs))
+	color.Cyan("          Total Instances: %d", results.TotalInstances)
+	color.Cyan("               Total AMIs: %d", results.TotalAMIs())
+	color.Green("            Private AMIs: %d", len(results.PrivateAMIs))
+	color.Green("  Public & Verified AMIs: %d", len(results.VerifiedAMIs))
+	color.Yellow("  AMIs w/ Unknown status: %d", len(results.UnknownAMIs))
+	color.Red("Public & Unverified AMIs: %d", len(results.UnverifiedAMIs))
+	color.Red("          Untrusted AMIs: %d", len(results.UntrustedAMIs))
+	color.Red("         Suspicious AMIs: %d", len(results.SuspiciousAMIs))
 
 	if output != "" {
+		meta := reporters.Metadata{
+			AccountID:   results.AccountID,
+			ScanTime:    scanTime,
+			Regions:     results.Regions,
+			ToolVersion: toolVersion,
+		}
+		for _, format := range formats {
+			if err := writeReport(format, output, len(formats) > 1, meta, results); err != nil {
+				color.Red("Error writing %s report: %v", format, err)
+			}
+		}
+	}
+}
+
+// writeReport renders results in format and writes them to the path
+// derived from output. When multiple formats were requested, each gets
+// its own file: output with its extension (if any) replaced by .<format>,
+// e.g. report.csv becomes report.json; a single format reuses output as-is
+// for backward compatibility with the original -output behavior.
+func writeReport(format, output string, multiple bool, meta reporters.Metadata, results *scanner.Results) error {
+	reporter, err := reporters.ForFormat(format)
+	if err != nil {
+		return err
+	}
+
+	path := output
+	if multiple {
+		path = fmt.Sprintf("%s.%s", strings.TrimSuffix(output, filepath.Ext(output)), format)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating output file: %w", err)
+	}
+	defer file.Close()
+
+	if err := reporter.Write(file, meta, results); err != nil {
+		return fmt.Errorf("writing %s report: %w", format, err)
+	}
+
+	if filepath.IsAbs(path) {
+		color.Green("Output written to %s", path)
+	} else {
+		wd, _ := os.Getwd()
+		color.Green("Output written to %s/%s", wd, path)
+	}
+	return nil
+}
+
+// exportToGraph ingests results into the Neo4j database at graphURI,
+// upserting every discovered AMI (and the instances using it) as nodes
+// and relationships.
+func exportToGraph(ctx context.Context, graphURI, graphUser, graphPassword string, results *scanner.Results) error {
+	ingestor, err := graph.NewIngestor(ctx, graph.Config{
+		URI:      graphURI,
+		Username: graphUser,
+		Password: graphPassword,
+	})
+	if err != nil {
+		return err
+	}
+	defer ingestor.Close(ctx)
+
+	if verbose {
+		fmt.Printf("[*] Exporting findings to Neo4j at %s\n", graphURI)
+	}
+	return ingestor.IngestResults(ctx, results)
+}
+
+// accountScanError records that scanning one account in a cross-account
+// run failed; it does not abort scanAccounts, only that account's
+// contribution to the merged results.
+type accountScanError struct {
+	AccountID string
+	Err       error
+}
+
+// scanAccounts assumes the role described in the -accounts manifest into
+// every target account (or, with organization set, every ACTIVE account
+// auto-discovered via organizations:ListAccounts) and scans each with its
+// own Scanner. Every account's AssumeRole call and Scanner share one
+// scanner.Limiter sized by cfg.Concurrency, so that ceiling bounds the
+// total number of concurrent AWS calls across all accounts and all their
+// regions, rather than each account, region and AMI-batch layer
+// multiplying the others. An account holds no slot while its Scanner runs.
+// A single account's failure to assume-role or scan is recorded and
+// skipped rather than aborting the whole run.
+func scanAccounts(ctx context.Context, manifestPath string, organization bool, cfg scanner.Config) (*scanner.Results, []accountScanError, error) {
+	manifest, err := accounts.LoadManifest(manifestPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	baseAWSCfg, err := scanner.LoadAWSConfig(ctx, cfg.Profile, cfg.MaxRetries)
+	if err != nil {
+		return nil, nil, fmt.Errorf("loading AWS config: %w", err)
+	}
 
-		file, err := os.Create(output)
+	targets := manifest.Accounts
+	if organization {
+		targets, err = accounts.Discover(ctx, baseAWSCfg, manifest.RoleName, manifest.ExternalID)
 		if err != nil {
-			color.Red("Error creating output file: %v", err)
-			os.Exit(1)
+			return nil, nil, fmt.Errorf("discovering organization accounts: %w", err)
 		}
-		defer file.Close()
+	}
 
-		_, err = file.WriteString("AMI ID|Region|whoAMI status|Public|Owner Alias|Owner ID|Name|Description\n")
-		for _, ami := range verifiedAMIs {
-			_, err = file.WriteString(fmt.Sprintf("%s|%s|Verified|%s|%s|%s|%s|%s\n", ami.ID, ami.Region, ami.Public, ami.OwnerAlias, ami.OwnerID, ami.Name, ami.Description))
-		}
-		for _, ami := range privateAMIs {
-			_, err = file.WriteString(fmt.Sprintf("%s|%s|Private|%s|%s|%s|%s|%s\n", ami.ID, ami.Region, ami.Public, ami.OwnerAlias, ami.OwnerID, ami.Name, ami.Description))
-		}
-		for _, ami := range unknownAMIs {
-			_, err = file.WriteString(fmt.Sprintf("%s|%s|Unknown|Unknown|Unknown|Unknown|Unknown\n", ami.ID, ami.Region))
-		}
-		for _, ami := range unverifiedAMIs {
-			_, err = file.WriteString(fmt.Sprintf("%s|%s|Unverified|%s|%s|%s|%s|%s\n", ami.ID, ami.Region, ami.Public, ami.OwnerAlias, ami.OwnerID, ami.Name, ami.Description))
-		}
-		// let the user know the file was written, but give them the full path. If the user have a full path print that, if they just gave a file name, print the full path using hte current direcotry
-		// this is to make it easier for the user to know where the file was written
-		if output[0] == '/' {
-			color.Green("Output written to %s", output)
-		} else {
-			wd, _ := os.Getwd()
-			color.Green("Output written to %s/%s", wd, output)
-		}
+	// Every account in the manifest (or discovered via -organization) is a
+	// sibling account this tool was explicitly told to scan, so AMIs it
+	// owns should classify as Verified rather than Unverified.
+	trustPolicy := cfg.TrustPolicy
+	if trustPolicy == nil {
+		trustPolicy = policy.Default()
+	}
+	orgAccountIDs := make([]string, 0, len(targets))
+	for _, account := range targets {
+		orgAccountIDs = append(orgAccountIDs, account.ID)
+	}
+	cfg.TrustPolicy = trustPolicy.WithOrganizationAccounts(orgAccountIDs)
+
+	limiter := scanner.NewLimiter(cfg.Concurrency)
+
+	var mu sync.Mutex
+	var allResults []*scanner.Results
+	var accountErrs []accountScanError
+
+	var wg sync.WaitGroup
+	for _, account := range targets {
+		account := account
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if verbose {
+				fmt.Printf("[*] Assuming role %s in account %s\n", account.RoleName, account.ID)
+			}
+			limiter.Acquire()
+			assumedCfg, err := accounts.AssumeRoleConfig(ctx, baseAWSCfg, account)
+			limiter.Release()
+			if err != nil {
+				mu.Lock()
+				accountErrs = append(accountErrs, accountScanError{AccountID: account.ID, Err: err})
+				mu.Unlock()
+				return
+			}
+			if cfg.Region != "" {
+				assumedCfg.Region = cfg.Region
+			}
+
+			results, err := scanner.NewFromConfigWithLimiter(assumedCfg, cfg, limiter).Run(ctx)
+			if err != nil {
+				mu.Lock()
+				accountErrs = append(accountErrs, accountScanError{AccountID: account.ID, Err: err})
+				mu.Unlock()
+				return
+			}
+
+			mu.Lock()
+			allResults = append(allResults, results)
+			mu.Unlock()
+		}()
 	}
+	wg.Wait()
+
+	return scanner.MergeResults(allResults), accountErrs, nil
 }
 
 // PreparePath ensures the output path is valid and all directories exist.
@@ -0,0 +1,108 @@
+package policy
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// recencyWindow is how new an AMI's CreationDate must be for that alone
+// to be treated as a suspicious signal: name-confusion AMIs are
+// typically registered shortly before being picked up by a victim's
+// misconfigured "most recent" AMI lookup.
+const recencyWindow = 30 * 24 * time.Hour
+
+// impersonationPattern maps a vendor-image Name pattern to the owner
+// IDs/aliases that are legitimately allowed to publish it.
+type impersonationPattern struct {
+	label            string
+	nameRegex        *regexp.Regexp
+	expectedOwnerIDs []string
+}
+
+// NameImpersonationDetector flags public AMIs whose Name mimics a
+// well-known vendor image (e.g. "amzn2-ami-hvm", "ubuntu/images/") but
+// whose owner is not one of that vendor's known accounts -- the whoAMI
+// name-confusion attack pattern.
+type NameImpersonationDetector struct {
+	patterns []impersonationPattern
+}
+
+// DefaultImpersonationDetector returns a detector covering the vendor
+// families most commonly impersonated in public whoAMI reports.
+func DefaultImpersonationDetector() *NameImpersonationDetector {
+	return &NameImpersonationDetector{patterns: []impersonationPattern{
+		{
+			label:            "amzn2-ami-hvm",
+			nameRegex:        regexp.MustCompile(`(?i)amzn2-ami-hvm`),
+			expectedOwnerIDs: []string{"137112412989", "amazon"},
+		},
+		{
+			label:            "ubuntu/images/",
+			nameRegex:        regexp.MustCompile(`(?i)ubuntu/images/`),
+			expectedOwnerIDs: []string{"099720109477"},
+		},
+		{
+			label:            "RHEL-",
+			nameRegex:        regexp.MustCompile(`(?i)^RHEL-`),
+			expectedOwnerIDs: []string{"309956199498"},
+		},
+		{
+			label:            "debian-",
+			nameRegex:        regexp.MustCompile(`(?i)^debian-`),
+			expectedOwnerIDs: []string{"136693071363"},
+		},
+	}}
+}
+
+// Detection describes a name-impersonation match for a single AMI.
+type Detection struct {
+	Pattern         string
+	ExpectedOwners  []string
+	ActualOwnerID   string
+	RecentlyCreated bool
+}
+
+// Reason renders a human-readable explanation of the detection, suitable
+// for the CSV/JSON Reason column.
+func (d Detection) Reason() string {
+	reason := fmt.Sprintf("AMI name matches vendor pattern %q but owner %s is not one of the expected owners %v", d.Pattern, d.ActualOwnerID, d.ExpectedOwners)
+	if d.RecentlyCreated {
+		reason += "; AMI was registered recently, a trait common to name-confusion attacks"
+	}
+	return reason
+}
+
+// Detect checks ami against every known vendor-name pattern. ok is false
+// when no pattern matches ami.Name. When a pattern matches but
+// ami.OwnerID/OwnerAlias is not one of that pattern's expected owners,
+// the returned Detection flags the impersonation; createdAt is used to
+// flag AMIs registered within recencyWindow of now.
+func (d *NameImpersonationDetector) Detect(ami AMIInfo, createdAt, now time.Time) (Detection, bool) {
+	for _, p := range d.patterns {
+		if !p.nameRegex.MatchString(ami.Name) {
+			continue
+		}
+		if ownerMatches(p.expectedOwnerIDs, ami.OwnerID, ami.OwnerAlias) {
+			continue // genuinely from the expected vendor account
+		}
+		return Detection{
+			Pattern:         p.label,
+			ExpectedOwners:  p.expectedOwnerIDs,
+			ActualOwnerID:   ami.OwnerID,
+			RecentlyCreated: !createdAt.IsZero() && now.Sub(createdAt) <= recencyWindow,
+		}, true
+	}
+	return Detection{}, false
+}
+
+func ownerMatches(expected []string, candidates ...string) bool {
+	for _, e := range expected {
+		for _, c := range candidates {
+			if e == c {
+				return true
+			}
+		}
+	}
+	return false
+}
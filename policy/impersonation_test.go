@@ -0,0 +1,95 @@
+package policy
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDetectFlagsImpersonatedOwner(t *testing.T) {
+	d := DefaultImpersonationDetector()
+
+	detection, ok := d.Detect(AMIInfo{
+		Name:       "amzn2-ami-hvm-2.0.20240101-x86_64-gp2",
+		OwnerID:    "999999999999",
+		OwnerAlias: "",
+	}, time.Time{}, time.Now())
+
+	if !ok {
+		t.Fatal("expected Detect to flag an AMI matching a vendor pattern from an unexpected owner")
+	}
+	if detection.Pattern != "amzn2-ami-hvm" {
+		t.Errorf("Pattern = %q, want %q", detection.Pattern, "amzn2-ami-hvm")
+	}
+	if detection.RecentlyCreated {
+		t.Error("RecentlyCreated = true, want false for a zero createdAt")
+	}
+}
+
+func TestDetectAllowsExpectedOwner(t *testing.T) {
+	d := DefaultImpersonationDetector()
+
+	_, ok := d.Detect(AMIInfo{
+		Name:    "amzn2-ami-hvm-2.0.20240101-x86_64-gp2",
+		OwnerID: "137112412989",
+	}, time.Time{}, time.Now())
+
+	if ok {
+		t.Fatal("expected Detect not to flag an AMI from the expected vendor owner")
+	}
+}
+
+func TestDetectAllowsExpectedOwnerAlias(t *testing.T) {
+	d := DefaultImpersonationDetector()
+
+	_, ok := d.Detect(AMIInfo{
+		Name:       "amzn2-ami-hvm-2.0.20240101-x86_64-gp2",
+		OwnerAlias: "amazon",
+	}, time.Time{}, time.Now())
+
+	if ok {
+		t.Fatal("expected Detect not to flag an AMI from the expected vendor owner alias")
+	}
+}
+
+func TestDetectNoPatternMatch(t *testing.T) {
+	d := DefaultImpersonationDetector()
+
+	_, ok := d.Detect(AMIInfo{Name: "my-totally-unrelated-image", OwnerID: "999999999999"}, time.Time{}, time.Now())
+	if ok {
+		t.Fatal("expected Detect not to flag an AMI whose name matches no known vendor pattern")
+	}
+}
+
+func TestDetectFlagsRecentlyCreatedAMI(t *testing.T) {
+	d := DefaultImpersonationDetector()
+	now := time.Now()
+
+	detection, ok := d.Detect(AMIInfo{
+		Name:    "RHEL-9.0-20240101",
+		OwnerID: "999999999999",
+	}, now.Add(-time.Hour), now)
+
+	if !ok {
+		t.Fatal("expected Detect to flag an AMI matching a vendor pattern from an unexpected owner")
+	}
+	if !detection.RecentlyCreated {
+		t.Error("RecentlyCreated = false, want true for an AMI created an hour ago")
+	}
+}
+
+func TestDetectDoesNotFlagOldAMI(t *testing.T) {
+	d := DefaultImpersonationDetector()
+	now := time.Now()
+
+	detection, ok := d.Detect(AMIInfo{
+		Name:    "debian-12-20200101",
+		OwnerID: "999999999999",
+	}, now.Add(-60*24*time.Hour), now)
+
+	if !ok {
+		t.Fatal("expected Detect to flag an AMI matching a vendor pattern from an unexpected owner")
+	}
+	if detection.RecentlyCreated {
+		t.Error("RecentlyCreated = true, want false for an AMI created 60 days ago")
+	}
+}
@@ -0,0 +1,175 @@
+// Package policy classifies AMIs as Verified, Unverified, Untrusted or
+// Private according to a configurable TrustPolicy; the "amazon" owner
+// alias is trusted under every policy. NameImpersonationDetector flags
+// vendor-name impersonation (Suspicious); Unknown is the status callers
+// give AMIs they could not describe, which Classify never returns.
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Classification is the whoAMI status assigned to an AMI.
+type Classification string
+
+const (
+	Verified   Classification = "Verified"
+	Unverified Classification = "Unverified"
+	Untrusted  Classification = "Untrusted"
+	Suspicious Classification = "Suspicious"
+	Private    Classification = "Private"
+	Unknown    Classification = "Unknown"
+)
+
+// AMIInfo is the subset of AMI metadata a TrustPolicy needs to classify
+// it. It is deliberately decoupled from scanner.AMI so this package has
+// no dependency on the scanner package.
+type AMIInfo struct {
+	ID         string
+	OwnerID    string
+	OwnerAlias string
+	Name       string
+	Public     bool
+}
+
+// rawPolicy is the on-disk (YAML or JSON) representation of a TrustPolicy.
+type rawPolicy struct {
+	TrustedOwnerIDs        []string `json:"trustedOwnerIDs" yaml:"trustedOwnerIDs"`
+	TrustedAliases         []string `json:"trustedAliases" yaml:"trustedAliases"`
+	OrganizationAccountIDs []string `json:"organizationAccountIDs" yaml:"organizationAccountIDs"`
+	DenyOwnerIDs           []string `json:"denyOwnerIDs" yaml:"denyOwnerIDs"`
+	FlagNamePatterns       []string `json:"flagNamePatterns" yaml:"flagNamePatterns"`
+}
+
+// TrustPolicy decides whether an AMI's owner should be trusted.
+type TrustPolicy struct {
+	trustedOwnerIDs map[string]bool
+	trustedAliases  map[string]bool
+	orgAccountIDs   map[string]bool
+	denyOwnerIDs    map[string]bool
+	flagNames       []*regexp.Regexp // Name patterns that flag an otherwise-unverified AMI
+}
+
+// Default returns the built-in policy covering Amazon and the major
+// public-vendor AMI accounts, used when -trust-policy is not set.
+func Default() *TrustPolicy {
+	// The built-in policy sets no FlagNamePatterns, so build never errors here.
+	p, _ := build(rawPolicy{
+		TrustedAliases: []string{"amazon"},
+		TrustedOwnerIDs: []string{
+			"099720109477", // Canonical (Ubuntu)
+			"309956199498", // Red Hat (RHEL)
+			"136693071363", // Debian
+			"013907871322", // SUSE
+			"125523088429", // CentOS
+		},
+	})
+	return p
+}
+
+// Load reads a TrustPolicy from a JSON or YAML file, selected by its
+// extension (.json, or .yml/.yaml).
+func Load(path string) (*TrustPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading trust policy %s: %w", path, err)
+	}
+
+	var raw rawPolicy
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		err = json.Unmarshal(data, &raw)
+	case ".yml", ".yaml":
+		err = yaml.Unmarshal(data, &raw)
+	default:
+		return nil, fmt.Errorf("unsupported trust policy extension %q (expected .json, .yml or .yaml)", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing trust policy %s: %w", path, err)
+	}
+
+	return build(raw)
+}
+
+func build(raw rawPolicy) (*TrustPolicy, error) {
+	flagNames := make([]*regexp.Regexp, 0, len(raw.FlagNamePatterns))
+	for _, pattern := range raw.FlagNamePatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("compiling flagNamePatterns entry %q: %w", pattern, err)
+		}
+		flagNames = append(flagNames, re)
+	}
+
+	p := &TrustPolicy{
+		trustedOwnerIDs: toSet(raw.TrustedOwnerIDs),
+		trustedAliases:  toSet(raw.TrustedAliases),
+		orgAccountIDs:   toSet(raw.OrganizationAccountIDs),
+		denyOwnerIDs:    toSet(raw.DenyOwnerIDs),
+		flagNames:       flagNames,
+	}
+	return p, nil
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
+
+// WithOrganizationAccounts returns a copy of p with accountIDs added to
+// the organization-account allowlist, e.g. after auto-discovering them
+// via organizations:ListAccounts.
+func (p *TrustPolicy) WithOrganizationAccounts(accountIDs []string) *TrustPolicy {
+	merged := *p
+	merged.orgAccountIDs = make(map[string]bool, len(p.orgAccountIDs)+len(accountIDs))
+	for id := range p.orgAccountIDs {
+		merged.orgAccountIDs[id] = true
+	}
+	for _, id := range accountIDs {
+		merged.orgAccountIDs[id] = true
+	}
+	return &merged
+}
+
+// Classify returns the Classification for ami along with a short,
+// human-readable reason explaining the decision.
+func (p *TrustPolicy) Classify(ami AMIInfo) (Classification, string) {
+	if !ami.Public {
+		if ami.OwnerAlias == "self" || p.orgAccountIDs[ami.OwnerID] {
+			return Private, "private AMI owned by this account or organization"
+		}
+		return Private, "private AMI"
+	}
+
+	if p.denyOwnerIDs[ami.OwnerID] {
+		return Untrusted, fmt.Sprintf("owner %s is explicitly denied by trust policy", ami.OwnerID)
+	}
+
+	if ami.OwnerAlias == "amazon" || p.trustedAliases[ami.OwnerAlias] {
+		return Verified, fmt.Sprintf("owner alias %q is trusted", ami.OwnerAlias)
+	}
+	if p.trustedOwnerIDs[ami.OwnerID] {
+		return Verified, fmt.Sprintf("owner ID %s is trusted", ami.OwnerID)
+	}
+	if p.orgAccountIDs[ami.OwnerID] {
+		return Verified, fmt.Sprintf("owner ID %s belongs to this organization", ami.OwnerID)
+	}
+
+	for _, pattern := range p.flagNames {
+		if pattern.MatchString(ami.Name) {
+			return Unverified, fmt.Sprintf("owner %s/%s is not trusted and AMI name matches flagged pattern %q", ami.OwnerID, ami.OwnerAlias, pattern.String())
+		}
+	}
+
+	return Unverified, fmt.Sprintf("owner %s/%s is not present in the trust policy", ami.OwnerID, ami.OwnerAlias)
+}
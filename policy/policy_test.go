@@ -0,0 +1,70 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestClassifyFlagNamePatternsUseRegex(t *testing.T) {
+	p, err := build(rawPolicy{
+		FlagNamePatterns: []string{`^suspicious-.*-\d+$`},
+	})
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	// Anchored match: reason should call out the flagged pattern.
+	_, reason := p.Classify(AMIInfo{OwnerID: "999999999999", Public: true, Name: "suspicious-ubuntu-123"})
+	if !strings.Contains(reason, "flagged pattern") {
+		t.Errorf("reason %q does not mention the flagged pattern for an anchored match", reason)
+	}
+
+	// An unanchored match would incorrectly flag this, since
+	// "suspicious-ubuntu-123" appears inside it; the ^...$ anchors
+	// mean a real regexp.MatchString must reject it.
+	_, reason = p.Classify(AMIInfo{OwnerID: "999999999999", Public: true, Name: "not-suspicious-ubuntu-123-really"})
+	if strings.Contains(reason, "flagged pattern") {
+		t.Errorf("reason %q incorrectly matched the anchored pattern via substring", reason)
+	}
+}
+
+func TestClassifyInvalidRegexFailsToBuild(t *testing.T) {
+	if _, err := build(rawPolicy{FlagNamePatterns: []string{"("}}); err == nil {
+		t.Fatal("expected build to reject an invalid regex pattern")
+	}
+}
+
+func TestClassifyTrustedAliasIsVerified(t *testing.T) {
+	p := Default()
+	got, _ := p.Classify(AMIInfo{OwnerAlias: "amazon", Public: true})
+	if got != Verified {
+		t.Fatalf("Classify(OwnerAlias=amazon) = %s, want %s", got, Verified)
+	}
+}
+
+func TestClassifyPrivateAMI(t *testing.T) {
+	p := Default()
+	got, _ := p.Classify(AMIInfo{OwnerAlias: "self", Public: false})
+	if got != Private {
+		t.Fatalf("Classify(Public=false, self) = %s, want %s", got, Private)
+	}
+}
+
+func TestClassifyDeniedOwnerIsUntrusted(t *testing.T) {
+	p, err := build(rawPolicy{DenyOwnerIDs: []string{"123456789012"}})
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	got, _ := p.Classify(AMIInfo{OwnerID: "123456789012", Public: true})
+	if got != Untrusted {
+		t.Fatalf("Classify(denied owner) = %s, want %s", got, Untrusted)
+	}
+}
+
+func TestWithOrganizationAccountsVerifiesMemberOwners(t *testing.T) {
+	p := Default().WithOrganizationAccounts([]string{"222222222222"})
+	got, _ := p.Classify(AMIInfo{OwnerID: "222222222222", Public: true})
+	if got != Verified {
+		t.Fatalf("Classify(org account owner) = %s, want %s", got, Verified)
+	}
+}
@@ -0,0 +1,34 @@
+package reporters
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/DataDog/whoAMI-scanner/scanner"
+)
+
+// CSVReporter renders results as the original pipe-delimited report.
+type CSVReporter struct{}
+
+func (CSVReporter) Format() string { return "csv" }
+
+func (CSVReporter) Write(w io.Writer, _ Metadata, results *scanner.Results) error {
+	if _, err := io.WriteString(w, "Account ID|AMI ID|Region|whoAMI status|Public|Owner Alias|Owner ID|Name|Description|Reason|Referenced By\n"); err != nil {
+		return err
+	}
+	for _, f := range findings(results) {
+		ami := f.ami
+		referencedBy := strings.Join(ami.ReferencedBy, ",")
+		if f.status == "Unknown" {
+			if _, err := fmt.Fprintf(w, "%s|%s|%s|Unknown|Unknown|Unknown|Unknown|Unknown|Unknown|%s|%s\n", ami.AccountID, ami.ID, ami.Region, ami.Reason, referencedBy); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "%s|%s|%s|%s|%s|%s|%s|%s|%s|%s|%s\n", ami.AccountID, ami.ID, ami.Region, f.status, ami.Public, ami.OwnerAlias, ami.OwnerID, ami.Name, ami.Description, ami.Reason, referencedBy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package reporters
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/DataDog/whoAMI-scanner/scanner"
+)
+
+// JSONReporter renders results as a single JSON document: scan metadata
+// plus a flat array of AMI findings, each carrying the instances (if
+// any) found running it.
+type JSONReporter struct{}
+
+func (JSONReporter) Format() string { return "json" }
+
+type jsonDocument struct {
+	AccountID   string        `json:"accountId"`
+	ScanTime    string        `json:"scanTime"`
+	Regions     []string      `json:"regions"`
+	ToolVersion string        `json:"toolVersion"`
+	Findings    []jsonFinding `json:"findings"`
+}
+
+type jsonFinding struct {
+	AMIID        string   `json:"amiId"`
+	AccountID    string   `json:"accountId"`
+	Region       string   `json:"region"`
+	Status       string   `json:"status"`
+	Public       string   `json:"public"`
+	OwnerAlias   string   `json:"ownerAlias"`
+	OwnerID      string   `json:"ownerId"`
+	Name         string   `json:"name"`
+	Description  string   `json:"description"`
+	Reason       string   `json:"reason"`
+	CreationDate string   `json:"creationDate,omitempty"`
+	Instances    []string `json:"instances,omitempty"`
+	ReferencedBy []string `json:"referencedBy,omitempty"`
+}
+
+func (JSONReporter) Write(w io.Writer, meta Metadata, results *scanner.Results) error {
+	doc := jsonDocument{
+		AccountID:   meta.AccountID,
+		ScanTime:    meta.ScanTime.UTC().Format("2006-01-02T15:04:05Z"),
+		Regions:     meta.Regions,
+		ToolVersion: meta.ToolVersion,
+	}
+	for _, f := range findings(results) {
+		ami := f.ami
+		doc.Findings = append(doc.Findings, jsonFinding{
+			AMIID:        ami.ID,
+			AccountID:    ami.AccountID,
+			Region:       ami.Region,
+			Status:       string(f.status),
+			Public:       ami.Public,
+			OwnerAlias:   ami.OwnerAlias,
+			OwnerID:      ami.OwnerID,
+			Name:         ami.Name,
+			Description:  ami.Description,
+			Reason:       ami.Reason,
+			CreationDate: ami.CreationDate,
+			Instances:    ami.Instances,
+			ReferencedBy: ami.ReferencedBy,
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
@@ -0,0 +1,84 @@
+// Package reporters renders scan results in the output formats
+// whoAMI-scanner supports (CSV, JSON, SARIF) behind a common Reporter
+// interface.
+package reporters
+
+import (
+	"io"
+	"time"
+
+	"github.com/DataDog/whoAMI-scanner/policy"
+	"github.com/DataDog/whoAMI-scanner/scanner"
+)
+
+// Metadata describes the scan that produced a set of findings; it is
+// embedded in the JSON document, and its ToolVersion is the SARIF tool
+// version. SARIF locations are built from each AMI's own account/region.
+type Metadata struct {
+	AccountID   string
+	ScanTime    time.Time
+	Regions     []string
+	ToolVersion string
+}
+
+// Reporter renders scan results to w in a specific format.
+type Reporter interface {
+	// Format is the -format value that selects this reporter (e.g. "csv").
+	Format() string
+	Write(w io.Writer, meta Metadata, results *scanner.Results) error
+}
+
+// ForFormat returns the built-in Reporter for name ("csv", "json" or
+// "sarif"), or an error if name is not recognized.
+func ForFormat(name string) (Reporter, error) {
+	switch name {
+	case "csv":
+		return CSVReporter{}, nil
+	case "json":
+		return JSONReporter{}, nil
+	case "sarif":
+		return SARIFReporter{}, nil
+	default:
+		return nil, unsupportedFormatError{name}
+	}
+}
+
+type unsupportedFormatError struct{ name string }
+
+func (e unsupportedFormatError) Error() string {
+	return "unsupported report format: " + e.name
+}
+
+// finding flattens a classified AMI (and the status/reason it was filed
+// under) for reporters that need a single list rather than the
+// per-status maps on scanner.Results.
+type finding struct {
+	status policy.Classification
+	ami    scanner.AMI
+}
+
+// findings flattens results into one slice ordered by classification
+// severity (most concerning first), which both JSON and SARIF reporters
+// render in that order.
+func findings(results *scanner.Results) []finding {
+	var all []finding
+	for _, ami := range results.SuspiciousAMIs {
+		all = append(all, finding{policy.Suspicious, ami})
+	}
+	for _, ami := range results.UntrustedAMIs {
+		all = append(all, finding{policy.Untrusted, ami})
+	}
+	for _, ami := range results.UnverifiedAMIs {
+		all = append(all, finding{policy.Unverified, ami})
+	}
+	for _, ami := range results.UnknownAMIs {
+		all = append(all, finding{policy.Unknown, ami})
+	}
+	for _, ami := range results.VerifiedAMIs {
+		all = append(all, finding{policy.Verified, ami})
+	}
+	for _, ami := range results.PrivateAMIs {
+		all = append(all, finding{policy.Private, ami})
+	}
+	return all
+}
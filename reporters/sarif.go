@@ -0,0 +1,164 @@
+package reporters
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/DataDog/whoAMI-scanner/policy"
+	"github.com/DataDog/whoAMI-scanner/scanner"
+)
+
+// SARIFReporter renders untrusted/unverified/suspicious/unknown AMIs as
+// SARIF results, one per instance running the AMI (or a single result
+// for an AMI no instance is running), so they can be uploaded to
+// GitHub code scanning, Datadog, or any other SARIF-aware dashboard.
+type SARIFReporter struct{}
+
+func (SARIFReporter) Format() string { return "sarif" }
+
+const sarifSchemaURI = "https://raw.githubusercontent.com/oasis-tcs/sarif-spec/master/Schemata/sarif-schema-2.1.0.json"
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name    string      `json:"name"`
+	Version string      `json:"version"`
+	Rules   []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string    `json:"id"`
+	ShortDescription sarifText `json:"shortDescription"`
+}
+
+type sarifText struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifText       `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+// sarifLocation carries both a physicalLocation and a logicalLocation for
+// the same finding. whoAMI findings aren't tied to a file in the repo, so
+// PhysicalLocation.ArtifactLocation.URI is a synthetic placeholder (the
+// finding's instance/AMI ARN) rather than a real source file -- but
+// GitHub code scanning's SARIF ingestion requires every result to carry
+// at least one physicalLocation, and silently drops results that only
+// have logicalLocations.
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation  `json:"physicalLocation"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifLogicalLocation struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+var sarifRules = []sarifRule{
+	{ID: "whoami.unverified-owner", ShortDescription: sarifText{Text: "Public AMI owned by an account not present in the trust policy"}},
+	{ID: "whoami.name-impersonation", ShortDescription: sarifText{Text: "Public AMI name impersonates a well-known vendor image"}},
+	{ID: "whoami.unknown-ami", ShortDescription: sarifText{Text: "AMI referenced by an instance could not be resolved (deleted or made private)"}},
+}
+
+// ruleFor maps a classification to the SARIF ruleId/level that best
+// describes it. ok is false for classifications that aren't findings
+// (Verified, Private).
+func ruleFor(status policy.Classification) (ruleID, level string, ok bool) {
+	switch status {
+	case policy.Suspicious:
+		return "whoami.name-impersonation", "error", true
+	case policy.Untrusted:
+		return "whoami.unverified-owner", "error", true
+	case policy.Unverified:
+		return "whoami.unverified-owner", "warning", true
+	case policy.Unknown:
+		return "whoami.unknown-ami", "note", true
+	default:
+		return "", "", false
+	}
+}
+
+func (SARIFReporter) Write(w io.Writer, meta Metadata, results *scanner.Results) error {
+	log := sarifLog{
+		Schema:  sarifSchemaURI,
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:    "whoAMI-scanner",
+				Version: meta.ToolVersion,
+				Rules:   sarifRules,
+			}},
+		}},
+	}
+
+	for _, f := range findings(results) {
+		ruleID, level, ok := ruleFor(f.status)
+		if !ok {
+			continue
+		}
+		message := fmt.Sprintf("AMI %s in %s: %s", f.ami.ID, f.ami.Region, f.ami.Reason)
+
+		instances := f.ami.Instances
+		if len(instances) == 0 {
+			instances = []string{""}
+		}
+		for _, instanceID := range instances {
+			arn := instanceARN(f.ami.AccountID, f.ami.Region, f.ami.ID, instanceID)
+			log.Runs[0].Results = append(log.Runs[0].Results, sarifResult{
+				RuleID:  ruleID,
+				Level:   level,
+				Message: sarifText{Text: message},
+				Locations: []sarifLocation{{
+					PhysicalLocation: sarifPhysicalLocation{
+						ArtifactLocation: sarifArtifactLocation{URI: arn},
+					},
+					LogicalLocations: []sarifLogicalLocation{{
+						FullyQualifiedName: arn,
+						Kind:               "instance",
+					}},
+				}},
+			})
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(log)
+}
+
+// instanceARN builds the ARN of the instance running an AMI, or the bare
+// AMI ARN when no instance is known to be running it (e.g. a Launch
+// Template reference rather than a running instance).
+func instanceARN(accountID, region, amiID, instanceID string) string {
+	if instanceID == "" {
+		return fmt.Sprintf("arn:aws:ec2:%s::image/%s", region, amiID)
+	}
+	return fmt.Sprintf("arn:aws:ec2:%s:%s:instance/%s", region, accountID, instanceID)
+}
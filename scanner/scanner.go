@@ -0,0 +1,631 @@
+// Package scanner contains the core whoAMI scanning logic: fanning out
+// across regions with a bounded worker pool, deduping AMI IDs, and
+// batching DescribeImages calls to stay within AWS API limits. The
+// workers that make AWS calls (source collectors and AMI-batch describes,
+// across every region and every per-account Scanner a cross-account
+// caller runs side by side) draw from a shared Limiter, so
+// Config.Concurrency caps the scan's total concurrent AWS calls rather
+// than each layer multiplying the rest.
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go/ptr"
+	"github.com/fatih/color"
+
+	"github.com/DataDog/whoAMI-scanner/policy"
+	"github.com/DataDog/whoAMI-scanner/sources"
+)
+
+// instanceReferencePrefix is how sources.InstancesSource tags its
+// Reference.ReferencedBy values; scanner strips it to populate AMI.Instances.
+const instanceReferencePrefix = "instance/"
+
+// maxImageIDsPerDescribeCall is the maximum number of ImageIds the EC2
+// DescribeImages API accepts in a single call.
+const maxImageIDsPerDescribeCall = 200
+
+// AMI describes a single AMI discovered during a scan.
+type AMI struct {
+	ID           string
+	AccountID    string // account the scan that found this AMI ran against
+	Region       string
+	OwnerAlias   string
+	OwnerID      string
+	Name         string
+	Description  string
+	Public       string
+	Instances    []string // IDs of instances found running this AMI
+	ReferencedBy []string // every source reference found (instances, launch templates, launch configs, ASGs, SSM parameters)
+	Reason       string   // why the TrustPolicy/detector assigned this AMI's classification
+	CreationDate string   // raw CreationDate reported by DescribeImages
+}
+
+// Config controls how a Scanner performs a scan.
+type Config struct {
+	Profile               string
+	Region                string // empty means scan every region returned by DescribeRegions
+	Concurrency           int    // ceiling on concurrent AWS API calls in flight at once, shared across every fan-out layer (see Limiter)
+	MaxRetries            int    // max attempts for the AWS SDK retryer
+	Verbose               bool
+	TrustPolicy           *policy.TrustPolicy               // nil means policy.Default()
+	ImpersonationDetector *policy.NameImpersonationDetector // nil means policy.DefaultImpersonationDetector()
+}
+
+// Limiter bounds the number of concurrent AWS API calls in flight across
+// every fan-out layer of a scan. Only the workers that make the calls
+// (source collectors, AMI-batch describes, per-account AssumeRole) hold a
+// slot; accounts and regions, which only wait on the workers below them,
+// hold none, so no holder ever waits for a slot of its own layer's
+// children. A single Limiter shared across layers means
+// -concurrency caps the scan's total footprint; without sharing, each
+// layer's own independent worker pool multiplies against the others, so
+// a cross-account scan could drive on the order of Concurrency^3
+// goroutines hitting EC2/ASG/SSM/STS at once.
+type Limiter struct {
+	tokens chan struct{}
+}
+
+// NewLimiter returns a Limiter allowing up to n concurrent holders (10
+// when n <= 0, matching the other Concurrency defaults in this package).
+func NewLimiter(n int) *Limiter {
+	if n <= 0 {
+		n = 10
+	}
+	return &Limiter{tokens: make(chan struct{}, n)}
+}
+
+// Acquire blocks until a slot is free.
+func (l *Limiter) Acquire() { l.tokens <- struct{}{} }
+
+// Release frees a slot acquired with Acquire.
+func (l *Limiter) Release() { <-l.tokens }
+
+// Results aggregates everything discovered across all scanned regions.
+type Results struct {
+	AccountID      string
+	Regions        []string // every region actually scanned, across all accounts
+	TotalInstances int
+	VerifiedAMIs   map[string]AMI
+	UnverifiedAMIs map[string]AMI
+	UntrustedAMIs  map[string]AMI
+	SuspiciousAMIs map[string]AMI
+	UnknownAMIs    map[string]AMI
+	PrivateAMIs    map[string]AMI
+}
+
+// TotalAMIs returns the number of distinct AMIs processed across all
+// categories.
+func (r *Results) TotalAMIs() int {
+	return len(r.VerifiedAMIs) + len(r.UnverifiedAMIs) + len(r.UntrustedAMIs) + len(r.SuspiciousAMIs) + len(r.UnknownAMIs) + len(r.PrivateAMIs)
+}
+
+// MergeResults combines the results of scanning multiple accounts into
+// one. AccountID is left blank on the merged Results since it no longer
+// identifies a single account; every AMI.AccountID still does.
+//
+// Entries are keyed by (AccountID, AMI ID) rather than AMI ID alone: the
+// same AMI ID commonly shows up in every account that can see it (e.g. a
+// public vendor AMI), each with its own instances running it, and keying
+// by ID alone would let one account's entry silently overwrite another's.
+func MergeResults(all []*Results) *Results {
+	merged := &Results{
+		VerifiedAMIs:   make(map[string]AMI),
+		UnverifiedAMIs: make(map[string]AMI),
+		UntrustedAMIs:  make(map[string]AMI),
+		SuspiciousAMIs: make(map[string]AMI),
+		UnknownAMIs:    make(map[string]AMI),
+		PrivateAMIs:    make(map[string]AMI),
+	}
+	seenRegions := make(map[string]bool)
+	for _, r := range all {
+		if r == nil {
+			continue
+		}
+		merged.TotalInstances += r.TotalInstances
+		for _, region := range r.Regions {
+			if !seenRegions[region] {
+				seenRegions[region] = true
+				merged.Regions = append(merged.Regions, region)
+			}
+		}
+		for _, ami := range r.VerifiedAMIs {
+			merged.VerifiedAMIs[mergeKey(ami)] = ami
+		}
+		for _, ami := range r.UnverifiedAMIs {
+			merged.UnverifiedAMIs[mergeKey(ami)] = ami
+		}
+		for _, ami := range r.UntrustedAMIs {
+			merged.UntrustedAMIs[mergeKey(ami)] = ami
+		}
+		for _, ami := range r.SuspiciousAMIs {
+			merged.SuspiciousAMIs[mergeKey(ami)] = ami
+		}
+		for _, ami := range r.UnknownAMIs {
+			merged.UnknownAMIs[mergeKey(ami)] = ami
+		}
+		for _, ami := range r.PrivateAMIs {
+			merged.PrivateAMIs[mergeKey(ami)] = ami
+		}
+	}
+	return merged
+}
+
+// mergeKey uniquely identifies an AMI finding within a single account's
+// scan, for use as the merged map key in MergeResults.
+func mergeKey(ami AMI) string {
+	return ami.AccountID + "/" + ami.ID
+}
+
+// Scanner performs a whoAMI scan against a single AWS account.
+type Scanner struct {
+	cfg           Config
+	awsCfg        aws.Config
+	policy        *policy.TrustPolicy
+	impersonation *policy.NameImpersonationDetector
+	limiter       *Limiter
+}
+
+// New builds a Scanner from cfg, loading AWS credentials for the given
+// profile (or the default credential chain when empty) and configuring
+// the SDK retryer/backoff.
+func New(ctx context.Context, cfg Config) (*Scanner, error) {
+	if cfg.MaxRetries <= 0 {
+		cfg.MaxRetries = 5
+	}
+
+	awsCfg, err := LoadAWSConfig(ctx, cfg.Profile, cfg.MaxRetries)
+	if err != nil {
+		return nil, fmt.Errorf("loading AWS config: %w", err)
+	}
+	if cfg.Region != "" {
+		awsCfg.Region = cfg.Region
+	}
+
+	return NewFromConfig(awsCfg, cfg), nil
+}
+
+// LoadAWSConfig loads the AWS SDK config for profile (or the default
+// credential chain when empty), configured with a retryer bounded by
+// maxRetries. Callers that need to scan a non-default region should set
+// Region on the returned aws.Config afterwards.
+func LoadAWSConfig(ctx context.Context, profile string, maxRetries int) (aws.Config, error) {
+	if maxRetries <= 0 {
+		maxRetries = 5
+	}
+	return config.LoadDefaultConfig(ctx,
+		config.WithSharedConfigProfile(profile),
+		config.WithRegion("us-east-1"),
+		config.WithRetryer(func() aws.Retryer {
+			return retry.NewStandard(func(o *retry.StandardOptions) {
+				o.MaxAttempts = maxRetries
+			})
+		}),
+	)
+}
+
+// NewFromConfig builds a Scanner that scans using an already-configured
+// aws.Config, e.g. credentials assumed into a different account via
+// accounts.AssumeRoleConfig for cross-account scanning. Concurrency and
+// policy defaults are applied exactly as New applies them. The Scanner
+// gets its own Limiter, sized by cfg.Concurrency; callers that run
+// several Scanners side by side (e.g. one per account) should use
+// NewFromConfigWithLimiter instead, so the accounts share a single
+// concurrency ceiling rather than each getting their own.
+func NewFromConfig(awsCfg aws.Config, cfg Config) *Scanner {
+	return NewFromConfigWithLimiter(awsCfg, cfg, NewLimiter(cfg.Concurrency))
+}
+
+// NewFromConfigWithLimiter is NewFromConfig, but the Scanner draws from
+// limiter for every concurrent AWS call it makes (source collectors,
+// AMI-batch describes) instead of creating its own. Pass the
+// same Limiter to multiple Scanners to bound their combined concurrency
+// to a single ceiling, e.g. across a cross-account scan's per-account
+// Scanners.
+func NewFromConfigWithLimiter(awsCfg aws.Config, cfg Config, limiter *Limiter) *Scanner {
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 10
+	}
+
+	trustPolicy := cfg.TrustPolicy
+	if trustPolicy == nil {
+		trustPolicy = policy.Default()
+	}
+	impersonationDetector := cfg.ImpersonationDetector
+	if impersonationDetector == nil {
+		impersonationDetector = policy.DefaultImpersonationDetector()
+	}
+
+	return &Scanner{cfg: cfg, awsCfg: awsCfg, policy: trustPolicy, impersonation: impersonationDetector, limiter: limiter}
+}
+
+// Run scans every configured region concurrently, with the AWS calls each
+// region makes bounded by s.limiter, and returns the aggregated results.
+func (s *Scanner) Run(ctx context.Context) (*Results, error) {
+	stsClient := sts.NewFromConfig(s.awsCfg)
+	callerIdentity, err := stsClient.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return nil, fmt.Errorf("fetching account ID: %w", err)
+	}
+
+	accountID := ptr.ToString(callerIdentity.Account)
+
+	regions, err := s.resolveRegions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	store := newResultStore()
+
+	var wg sync.WaitGroup
+	for _, region := range regions {
+		region := region
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if s.cfg.Verbose {
+				fmt.Printf("[*] Checking region %s\n", region)
+			}
+			if err := s.scanRegion(ctx, region, accountID, store); err != nil {
+				color.Red("Error scanning region %s: %v", region, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	results := store.snapshot()
+	results.AccountID = accountID
+	results.Regions = regions
+	return results, nil
+}
+
+// resolveRegions returns the single configured region, or every region
+// returned by DescribeRegions when none was configured.
+func (s *Scanner) resolveRegions(ctx context.Context) ([]string, error) {
+	if s.cfg.Region != "" {
+		return []string{s.cfg.Region}, nil
+	}
+
+	ec2Client := ec2.NewFromConfig(s.awsCfg)
+	describeRegionsOutput, err := ec2Client.DescribeRegions(ctx, &ec2.DescribeRegionsInput{})
+	if err != nil {
+		return nil, fmt.Errorf("fetching regions: %w", err)
+	}
+
+	regions := make([]string, 0, len(describeRegionsOutput.Regions))
+	for _, r := range describeRegionsOutput.Regions {
+		regions = append(regions, ptr.ToString(r.RegionName))
+	}
+	return regions, nil
+}
+
+// scanRegion collects every AMI reference in region from all sources
+// (see collectReferences), dedupes the referenced AMI IDs against AMIs
+// already processed in this or other regions, then fans the
+// remaining IDs out to DescribeImages calls (up to
+// maxImageIDsPerDescribeCall IDs per call), each bounded by s.limiter
+// alongside every other concurrent AWS call this scan makes.
+func (s *Scanner) scanRegion(ctx context.Context, region, accountID string, store *resultStore) error {
+	client := ec2.NewFromConfig(s.awsCfg, func(o *ec2.Options) {
+		o.Region = region
+	})
+
+	amiIDs, err := s.collectReferences(ctx, region, store)
+	if err != nil {
+		return err
+	}
+	if len(amiIDs) == 0 {
+		return nil
+	}
+
+	var workers sync.WaitGroup
+	for i := 0; i < len(amiIDs); i += maxImageIDsPerDescribeCall {
+		end := i + maxImageIDsPerDescribeCall
+		if end > len(amiIDs) {
+			end = len(amiIDs)
+		}
+		batch := amiIDs[i:end]
+		workers.Add(1)
+		s.limiter.Acquire()
+		go func() {
+			defer workers.Done()
+			defer s.limiter.Release()
+			s.describeAndClassify(ctx, client, region, accountID, batch, store)
+		}()
+	}
+	workers.Wait()
+
+	return nil
+}
+
+// collectReferences runs every sources.Source concurrently against
+// region, bounded by s.limiter, recording every (AMI ID, referencedBy)
+// tuple it emits in store, and returns the distinct AMI IDs not yet
+// processed by another region.
+func (s *Scanner) collectReferences(ctx context.Context, region string, store *resultStore) ([]string, error) {
+	refs := make(chan sources.Reference, 100)
+
+	var collectors sync.WaitGroup
+	for _, src := range sources.All() {
+		src := src
+		collectors.Add(1)
+		s.limiter.Acquire()
+		go func() {
+			defer collectors.Done()
+			defer s.limiter.Release()
+			if err := src.Collect(ctx, s.awsCfg, region, refs); err != nil {
+				if s.cfg.Verbose {
+					color.Yellow("[%s] %s: %v", region, src.Name(), err)
+				}
+			}
+		}()
+	}
+	go func() {
+		collectors.Wait()
+		close(refs)
+	}()
+
+	var amiIDs []string
+	for ref := range refs {
+		if ref.AMIID == "" {
+			continue
+		}
+		if strings.HasPrefix(ref.ReferencedBy, instanceReferencePrefix) {
+			store.addInstance()
+			store.addInstanceRef(ref.AMIID, strings.TrimPrefix(ref.ReferencedBy, instanceReferencePrefix))
+		}
+		store.addReference(ref.AMIID, ref.ReferencedBy)
+
+		if store.markProcessed(ref.AMIID) {
+			if s.cfg.Verbose {
+				color.Cyan("[%s] %s already processed. Skipping.", region, ref.AMIID)
+			}
+			continue
+		}
+		amiIDs = append(amiIDs, ref.AMIID)
+	}
+	return amiIDs, nil
+}
+
+// describeAndClassify resolves a batch of AMI IDs via a single
+// DescribeImages call and files each one into the appropriate bucket in
+// store. IDs absent from the response are classified as unknown (the AMI
+// has been deleted or made private).
+func (s *Scanner) describeAndClassify(ctx context.Context, client *ec2.Client, region, accountID string, batch []string, store *resultStore) {
+	imageOutput, err := client.DescribeImages(ctx, &ec2.DescribeImagesInput{ImageIds: batch})
+	if err != nil {
+		if s.cfg.Verbose {
+			color.Red("Error fetching AMI details for %v: %v", batch, err)
+		}
+		return
+	}
+
+	found := make(map[string]bool, len(imageOutput.Images))
+	for _, image := range imageOutput.Images {
+		amiID := ptr.ToString(image.ImageId)
+		found[amiID] = true
+
+		publicString := "Private"
+		if ptr.ToBool(image.Public) {
+			publicString = "Public"
+		}
+		ami := AMI{
+			ID:           amiID,
+			AccountID:    accountID,
+			Region:       region,
+			OwnerAlias:   ptr.ToString(image.ImageOwnerAlias),
+			OwnerID:      ptr.ToString(image.OwnerId),
+			Name:         ptr.ToString(image.Name),
+			Description:  ptr.ToString(image.Description),
+			Public:       publicString,
+			CreationDate: ptr.ToString(image.CreationDate),
+		}
+
+		amiInfo := policy.AMIInfo{
+			ID:         ami.ID,
+			OwnerID:    ami.OwnerID,
+			OwnerAlias: ami.OwnerAlias,
+			Name:       ami.Name,
+			Public:     ptr.ToBool(image.Public),
+		}
+		classification, reason := s.policy.Classify(amiInfo)
+
+		if classification == policy.Unverified {
+			if detection, ok := s.impersonation.Detect(amiInfo, parseCreationDate(ami.CreationDate), time.Now()); ok {
+				classification = policy.Suspicious
+				reason = detection.Reason()
+			}
+		}
+		ami.Reason = reason
+
+		switch classification {
+		case policy.Verified:
+			if s.cfg.Verbose {
+				color.Green("[%s] %s is a community AMI from a verified account: %s", region, amiID, reason)
+			}
+			store.addVerified(ami)
+		case policy.Private:
+			if s.cfg.Verbose {
+				color.Green("[%s] %s is private: %s", region, amiID, reason)
+			}
+			store.addPrivate(ami)
+		case policy.Untrusted:
+			color.Red("[%s] %s is from a denylisted owner: %s", region, amiID, reason)
+			store.addUntrusted(ami)
+		case policy.Suspicious:
+			color.Red("[%s] %s looks like a whoAMI name-impersonation attempt: %s", region, amiID, reason)
+			store.addSuspicious(ami)
+		default:
+			color.Red("[%s] %s is a community AMI from an unverified account: %s", region, amiID, reason)
+			store.addUnverified(ami)
+		}
+	}
+
+	for _, amiID := range batch {
+		if found[amiID] {
+			continue
+		}
+		color.Yellow("[%s] %s has been deleted or made private.", region, amiID)
+		store.addUnknown(AMI{
+			ID:          amiID,
+			AccountID:   accountID,
+			Region:      region,
+			OwnerAlias:  "Unknown",
+			Public:      "Unknown",
+			OwnerID:     "Unknown",
+			Name:        "Unknown",
+			Description: "Unknown",
+			Reason:      "AMI no longer exists or is not accessible",
+		})
+	}
+}
+
+// parseCreationDate parses the CreationDate reported by DescribeImages
+// (RFC 3339, e.g. "2023-05-12T13:34:56.000Z"). It returns the zero
+// time.Time when raw is empty or malformed.
+func parseCreationDate(raw string) time.Time {
+	t, err := time.Parse(time.RFC3339Nano, raw)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// resultStore holds scan results behind a mutex so concurrent region and
+// AMI-batch workers can safely update it.
+type resultStore struct {
+	mu             sync.Mutex
+	totalInstances int
+	processedAMIs  map[string]bool
+	instanceRefs   map[string][]string // amiID -> instance IDs running it
+	referencedBy   map[string][]string // amiID -> every source reference (instances, LTs, LCs, ASGs, SSM params)
+	verifiedAMIs   map[string]AMI
+	unverifiedAMIs map[string]AMI
+	untrustedAMIs  map[string]AMI
+	suspiciousAMIs map[string]AMI
+	unknownAMIs    map[string]AMI
+	privateAMIs    map[string]AMI
+}
+
+func newResultStore() *resultStore {
+	return &resultStore{
+		processedAMIs:  make(map[string]bool),
+		instanceRefs:   make(map[string][]string),
+		referencedBy:   make(map[string][]string),
+		verifiedAMIs:   make(map[string]AMI),
+		unverifiedAMIs: make(map[string]AMI),
+		untrustedAMIs:  make(map[string]AMI),
+		suspiciousAMIs: make(map[string]AMI),
+		unknownAMIs:    make(map[string]AMI),
+		privateAMIs:    make(map[string]AMI),
+	}
+}
+
+func (s *resultStore) addInstance() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.totalInstances++
+}
+
+// addInstanceRef records that instanceID is running amiID, regardless of
+// whether amiID has already been (or will be) described elsewhere.
+func (s *resultStore) addInstanceRef(amiID, instanceID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.instanceRefs[amiID] = append(s.instanceRefs[amiID], instanceID)
+}
+
+// addReference records that amiID was referenced by referencedBy (e.g.
+// "instance/i-0123", "launch-template/lt-0123:2"), regardless of whether
+// amiID has already been (or will be) described elsewhere.
+func (s *resultStore) addReference(amiID, referencedBy string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.referencedBy[amiID] = append(s.referencedBy[amiID], referencedBy)
+}
+
+// markProcessed atomically checks whether amiID has already been claimed
+// by another worker and, if not, claims it. It returns true when the ID
+// was already processed.
+func (s *resultStore) markProcessed(amiID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.processedAMIs[amiID] {
+		return true
+	}
+	s.processedAMIs[amiID] = true
+	return false
+}
+
+func (s *resultStore) addVerified(ami AMI) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.verifiedAMIs[ami.ID] = ami
+}
+
+func (s *resultStore) addUnverified(ami AMI) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unverifiedAMIs[ami.ID] = ami
+}
+
+func (s *resultStore) addUntrusted(ami AMI) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.untrustedAMIs[ami.ID] = ami
+}
+
+func (s *resultStore) addSuspicious(ami AMI) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.suspiciousAMIs[ami.ID] = ami
+}
+
+func (s *resultStore) addUnknown(ami AMI) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unknownAMIs[ami.ID] = ami
+}
+
+func (s *resultStore) addPrivate(ami AMI) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.privateAMIs[ami.ID] = ami
+}
+
+func (s *resultStore) snapshot() *Results {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &Results{
+		TotalInstances: s.totalInstances,
+		VerifiedAMIs:   s.enrich(s.verifiedAMIs),
+		UnverifiedAMIs: s.enrich(s.unverifiedAMIs),
+		UntrustedAMIs:  s.enrich(s.untrustedAMIs),
+		SuspiciousAMIs: s.enrich(s.suspiciousAMIs),
+		UnknownAMIs:    s.enrich(s.unknownAMIs),
+		PrivateAMIs:    s.enrich(s.privateAMIs),
+	}
+}
+
+// enrich returns a copy of amis with each entry's Instances and
+// ReferencedBy fields populated from instanceRefs/referencedBy. Callers
+// must hold s.mu.
+func (s *resultStore) enrich(amis map[string]AMI) map[string]AMI {
+	out := make(map[string]AMI, len(amis))
+	for id, ami := range amis {
+		ami.Instances = s.instanceRefs[id]
+		ami.ReferencedBy = s.referencedBy[id]
+		out[id] = ami
+	}
+	return out
+}
@@ -0,0 +1,100 @@
+package scanner
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMergeResultsKeepsSameAMIFromDifferentAccounts(t *testing.T) {
+	shared := "ami-0123456789abcdef0"
+
+	accountA := &Results{
+		AccountID:      "111111111111",
+		TotalInstances: 1,
+		UnverifiedAMIs: map[string]AMI{
+			shared: {ID: shared, AccountID: "111111111111", Instances: []string{"i-aaa"}},
+		},
+	}
+	accountB := &Results{
+		AccountID:      "222222222222",
+		TotalInstances: 1,
+		UnverifiedAMIs: map[string]AMI{
+			shared: {ID: shared, AccountID: "222222222222", Instances: []string{"i-bbb"}},
+		},
+	}
+
+	merged := MergeResults([]*Results{accountA, accountB})
+
+	if got := merged.TotalInstances; got != 2 {
+		t.Fatalf("TotalInstances = %d, want 2", got)
+	}
+	if got := len(merged.UnverifiedAMIs); got != 2 {
+		t.Fatalf("len(UnverifiedAMIs) = %d, want 2 (one entry per account for shared AMI %s)", got, shared)
+	}
+
+	var seenInstances []string
+	for _, ami := range merged.UnverifiedAMIs {
+		if ami.ID != shared {
+			t.Fatalf("unexpected AMI ID %s in merged results", ami.ID)
+		}
+		seenInstances = append(seenInstances, ami.Instances...)
+	}
+	if len(seenInstances) != 2 {
+		t.Fatalf("expected instances from both accounts to survive the merge, got %v", seenInstances)
+	}
+}
+
+func TestMergeResultsSkipsNilResults(t *testing.T) {
+	merged := MergeResults([]*Results{nil, {TotalInstances: 3}, nil})
+	if merged.TotalInstances != 3 {
+		t.Fatalf("TotalInstances = %d, want 3", merged.TotalInstances)
+	}
+}
+
+func TestLimiterBoundsConcurrentHolders(t *testing.T) {
+	limiter := NewLimiter(2)
+	var inFlight, maxInFlight int32
+	var done sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		done.Add(1)
+		limiter.Acquire()
+		go func() {
+			defer done.Done()
+			defer limiter.Release()
+			n := atomic.AddInt32(&inFlight, 1)
+			for {
+				max := atomic.LoadInt32(&maxInFlight)
+				if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&inFlight, -1)
+		}()
+	}
+	done.Wait()
+
+	if maxInFlight > 2 {
+		t.Fatalf("max concurrent holders = %d, want <= 2", maxInFlight)
+	}
+}
+
+func TestMergeResultsDedupesRegions(t *testing.T) {
+	accountA := &Results{Regions: []string{"us-east-1", "us-west-2"}}
+	accountB := &Results{Regions: []string{"us-west-2", "eu-west-1"}}
+
+	merged := MergeResults([]*Results{accountA, accountB})
+
+	want := []string{"us-east-1", "us-west-2", "eu-west-1"}
+	if len(merged.Regions) != len(want) {
+		t.Fatalf("Regions = %v, want %v", merged.Regions, want)
+	}
+	for i, region := range want {
+		if merged.Regions[i] != region {
+			t.Fatalf("Regions = %v, want %v", merged.Regions, want)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package sources
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	asgtypes "github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/smithy-go/ptr"
+)
+
+// ASGSource collects the AMI ID backing each Auto Scaling Group,
+// resolving it through the group's Launch Template, mixed-instances
+// Launch Template, or (classic) Launch Configuration.
+type ASGSource struct{}
+
+func (ASGSource) Name() string { return "auto-scaling-groups" }
+
+func (ASGSource) Collect(ctx context.Context, cfg aws.Config, region string, out chan<- Reference) error {
+	regional := regionalConfig(cfg, region)
+	asgClient := autoscaling.NewFromConfig(regional)
+	ec2Client := ec2.NewFromConfig(regional)
+
+	paginator := autoscaling.NewDescribeAutoScalingGroupsPaginator(asgClient, &autoscaling.DescribeAutoScalingGroupsInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("describing auto scaling groups: %w", err)
+		}
+
+		for _, asg := range page.AutoScalingGroups {
+			referencedBy := fmt.Sprintf("asg/%s", ptr.ToString(asg.AutoScalingGroupName))
+
+			amiID, ok := resolveASGImage(ctx, ec2Client, asgClient, asg)
+			if !ok {
+				continue
+			}
+			out <- Reference{AMIID: amiID, Region: region, ReferencedBy: referencedBy}
+		}
+	}
+	return nil
+}
+
+// resolveASGImage finds the AMI ID the ASG will launch instances from.
+// It returns ok=false when the group's launch source can't be resolved
+// (e.g. a spec this tool doesn't recognize, or a lookup failure).
+func resolveASGImage(ctx context.Context, ec2Client *ec2.Client, asgClient *autoscaling.Client, asg asgtypes.AutoScalingGroup) (string, bool) {
+	switch {
+	case asg.LaunchTemplate != nil:
+		return resolveLaunchTemplateImage(ctx, ec2Client, asg.LaunchTemplate.LaunchTemplateId, asg.LaunchTemplate.LaunchTemplateName, asg.LaunchTemplate.Version)
+	case asg.MixedInstancesPolicy != nil && asg.MixedInstancesPolicy.LaunchTemplate != nil && asg.MixedInstancesPolicy.LaunchTemplate.LaunchTemplateSpecification != nil:
+		spec := asg.MixedInstancesPolicy.LaunchTemplate.LaunchTemplateSpecification
+		return resolveLaunchTemplateImage(ctx, ec2Client, spec.LaunchTemplateId, spec.LaunchTemplateName, spec.Version)
+	case asg.LaunchConfigurationName != nil:
+		return resolveLaunchConfigurationImage(ctx, asgClient, *asg.LaunchConfigurationName)
+	default:
+		return "", false
+	}
+}
+
+func resolveLaunchTemplateImage(ctx context.Context, client *ec2.Client, id, name, version *string) (string, bool) {
+	versions := []string{"$Default"}
+	if version != nil && *version != "" {
+		versions = []string{*version}
+	}
+
+	output, err := client.DescribeLaunchTemplateVersions(ctx, &ec2.DescribeLaunchTemplateVersionsInput{
+		LaunchTemplateId:   id,
+		LaunchTemplateName: name,
+		Versions:           versions,
+	})
+	if err != nil || len(output.LaunchTemplateVersions) == 0 {
+		return "", false
+	}
+
+	data := output.LaunchTemplateVersions[0].LaunchTemplateData
+	if data == nil || data.ImageId == nil {
+		return "", false
+	}
+	return *data.ImageId, true
+}
+
+func resolveLaunchConfigurationImage(ctx context.Context, client *autoscaling.Client, name string) (string, bool) {
+	output, err := client.DescribeLaunchConfigurations(ctx, &autoscaling.DescribeLaunchConfigurationsInput{
+		LaunchConfigurationNames: []string{name},
+	})
+	if err != nil || len(output.LaunchConfigurations) == 0 {
+		return "", false
+	}
+	return ptr.ToString(output.LaunchConfigurations[0].ImageId), true
+}
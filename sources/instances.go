@@ -0,0 +1,38 @@
+package sources
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/smithy-go/ptr"
+)
+
+// InstancesSource collects the AMI IDs backing every running instance in
+// a region.
+type InstancesSource struct{}
+
+func (InstancesSource) Name() string { return "instances" }
+
+func (InstancesSource) Collect(ctx context.Context, cfg aws.Config, region string, out chan<- Reference) error {
+	client := ec2.NewFromConfig(regionalConfig(cfg, region))
+
+	paginator := ec2.NewDescribeInstancesPaginator(client, &ec2.DescribeInstancesInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("describing instances: %w", err)
+		}
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				out <- Reference{
+					AMIID:        ptr.ToString(instance.ImageId),
+					Region:       region,
+					ReferencedBy: fmt.Sprintf("instance/%s", ptr.ToString(instance.InstanceId)),
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package sources
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	"github.com/aws/smithy-go/ptr"
+)
+
+// LaunchConfigurationSource collects the AMI ID referenced by every
+// (classic) Launch Configuration in a region.
+type LaunchConfigurationSource struct{}
+
+func (LaunchConfigurationSource) Name() string { return "launch-configurations" }
+
+func (LaunchConfigurationSource) Collect(ctx context.Context, cfg aws.Config, region string, out chan<- Reference) error {
+	client := autoscaling.NewFromConfig(regionalConfig(cfg, region))
+
+	paginator := autoscaling.NewDescribeLaunchConfigurationsPaginator(client, &autoscaling.DescribeLaunchConfigurationsInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("describing launch configurations: %w", err)
+		}
+		for _, lc := range page.LaunchConfigurations {
+			out <- Reference{
+				AMIID:        ptr.ToString(lc.ImageId),
+				Region:       region,
+				ReferencedBy: fmt.Sprintf("launch-configuration/%s", ptr.ToString(lc.LaunchConfigurationName)),
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package sources
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/smithy-go/ptr"
+)
+
+// LaunchTemplateSource collects the AMI IDs referenced by every version
+// of every Launch Template in a region.
+type LaunchTemplateSource struct{}
+
+func (LaunchTemplateSource) Name() string { return "launch-templates" }
+
+func (LaunchTemplateSource) Collect(ctx context.Context, cfg aws.Config, region string, out chan<- Reference) error {
+	client := ec2.NewFromConfig(regionalConfig(cfg, region))
+
+	templatesPaginator := ec2.NewDescribeLaunchTemplatesPaginator(client, &ec2.DescribeLaunchTemplatesInput{})
+	for templatesPaginator.HasMorePages() {
+		page, err := templatesPaginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("describing launch templates: %w", err)
+		}
+		for _, lt := range page.LaunchTemplates {
+			ltID := ptr.ToString(lt.LaunchTemplateId)
+
+			versionsPaginator := ec2.NewDescribeLaunchTemplateVersionsPaginator(client, &ec2.DescribeLaunchTemplateVersionsInput{
+				LaunchTemplateId: lt.LaunchTemplateId,
+				Versions:         []string{"$All"},
+			})
+			for versionsPaginator.HasMorePages() {
+				versionsPage, err := versionsPaginator.NextPage(ctx)
+				if err != nil {
+					return fmt.Errorf("describing versions of launch template %s: %w", ltID, err)
+				}
+				for _, version := range versionsPage.LaunchTemplateVersions {
+					if version.LaunchTemplateData == nil || version.LaunchTemplateData.ImageId == nil {
+						continue
+					}
+					out <- Reference{
+						AMIID:        ptr.ToString(version.LaunchTemplateData.ImageId),
+						Region:       region,
+						ReferencedBy: fmt.Sprintf("launch-template/%s:%d", ltID, ptr.ToInt64(version.VersionNumber)),
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
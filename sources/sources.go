@@ -0,0 +1,47 @@
+// Package sources collects AMI references from instances and from
+// places other than instances: Launch Templates, Launch Configurations,
+// Auto Scaling Groups and SSM parameters. A stale or attacker-planted AMI ID
+// referenced by one of these is invisible until something actually
+// launches, so each Source surfaces the reference up front.
+package sources
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// Reference ties an AMI ID to whatever referenced it, independent of
+// whether an instance is currently running it.
+type Reference struct {
+	AMIID        string
+	Region       string
+	ReferencedBy string // e.g. "instance/i-0123", "launch-template/lt-0123:2", "asg/my-asg"
+}
+
+// Source collects AMI references from one kind of AWS resource and
+// emits them onto out. Collect must close no channels; the caller owns
+// out and closes it once every Source has returned.
+type Source interface {
+	// Name identifies the source for logging, e.g. "launch-templates".
+	Name() string
+	Collect(ctx context.Context, cfg aws.Config, region string, out chan<- Reference) error
+}
+
+// All returns every built-in Source. The scanner runs them concurrently,
+// so the order of this list does not set the order of their references.
+func All() []Source {
+	return []Source{
+		InstancesSource{},
+		LaunchTemplateSource{},
+		LaunchConfigurationSource{},
+		ASGSource{},
+		SSMParameterSource{},
+	}
+}
+
+func regionalConfig(cfg aws.Config, region string) aws.Config {
+	regional := cfg.Copy()
+	regional.Region = region
+	return regional
+}
@@ -0,0 +1,48 @@
+package sources
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/aws/smithy-go/ptr"
+)
+
+// SSMParameterSource collects the AMI IDs held by SSM parameters of
+// type aws:ec2:image -- the pattern used by `{{resolve:ssm:/path}}`
+// references in CloudFormation/Terraform and by EC2 Image Builder.
+type SSMParameterSource struct{}
+
+func (SSMParameterSource) Name() string { return "ssm-parameters" }
+
+func (SSMParameterSource) Collect(ctx context.Context, cfg aws.Config, region string, out chan<- Reference) error {
+	client := ssm.NewFromConfig(regionalConfig(cfg, region))
+
+	paginator := ssm.NewDescribeParametersPaginator(client, &ssm.DescribeParametersInput{
+		ParameterFilters: []ssmtypes.ParameterStringFilter{{
+			Key:    ptr.String("DataType"),
+			Values: []string{"aws:ec2:image"},
+		}},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("describing SSM parameters: %w", err)
+		}
+		for _, param := range page.Parameters {
+			name := ptr.ToString(param.Name)
+			getOutput, err := client.GetParameter(ctx, &ssm.GetParameterInput{Name: param.Name})
+			if err != nil {
+				continue
+			}
+			out <- Reference{
+				AMIID:        ptr.ToString(getOutput.Parameter.Value),
+				Region:       region,
+				ReferencedBy: fmt.Sprintf("ssm-parameter/%s", name),
+			}
+		}
+	}
+	return nil
+}